@@ -29,6 +29,7 @@ import (
 	gds "github.com/trisacrypto/trisa/pkg/trisa/gds/api/v1beta1"
 	models "github.com/trisacrypto/trisa/pkg/trisa/gds/models/v1beta1"
 	"github.com/trisacrypto/trisa/pkg/trisa/mtls"
+	"github.com/trisacrypto/trisa/pkg/trisa/retention"
 	"github.com/trisacrypto/trisa/pkg/trust"
 	cli "github.com/urfave/cli/v2"
 	"google.golang.org/grpc"
@@ -404,6 +405,79 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:      "archive",
+			Usage:     "archive an unsealed secure envelope for data retention",
+			UsageText: "trisa archive -i unsealed_envelope.json -a archive/ -J US",
+			Action:    archiveEnvelope,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "in",
+					Aliases:  []string{"i"},
+					Usage:    "path to unsealed secure envelope to archive",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:    "archive",
+					Aliases: []string{"a"},
+					Usage:   "path to the directory where archived records are stored",
+					Value:   "archive",
+				},
+				&cli.StringFlag{
+					Name:        "keys",
+					Aliases:     []string{"K"},
+					Usage:       "path to the directory where wrapping keys are stored",
+					DefaultText: "[archive]/keys",
+				},
+				&cli.StringFlag{
+					Name:    "jurisdiction",
+					Aliases: []string{"J"},
+					Usage:   "country code of the jurisdiction whose retention rules apply",
+				},
+			},
+		},
+		{
+			Name:      "purge",
+			Usage:     "crypto-shred archived envelopes whose retention period has elapsed",
+			UsageText: "trisa purge -a archive/ -p policies.json\ntrisa purge -a archive/ -p policies.json --dry-run",
+			Action:    purge,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "archive",
+					Aliases: []string{"a"},
+					Usage:   "path to the directory where archived records are stored",
+					Value:   "archive",
+				},
+				&cli.StringFlag{
+					Name:        "keys",
+					Aliases:     []string{"K"},
+					Usage:       "path to the directory where wrapping keys are stored",
+					DefaultText: "[archive]/keys",
+				},
+				&cli.StringFlag{
+					Name:     "policies",
+					Aliases:  []string{"p"},
+					Usage:    "path to JSON file with retention policies to apply",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:    "dry-run",
+					Aliases: []string{"n"},
+					Usage:   "report the envelopes that would be purged without shredding them",
+				},
+				&cli.TimestampFlag{
+					Name:        "as-of",
+					Usage:       "apply retention policies as of the specified time",
+					Layout:      time.RFC3339,
+					DefaultText: "now",
+				},
+				&cli.StringFlag{
+					Name:    "out",
+					Aliases: []string{"o"},
+					Usage:   "path to write the purge report to as JSON",
+				},
+			},
+		},
 	}
 	app.Run(os.Args)
 }
@@ -877,6 +951,86 @@ func search(c *cli.Context) (err error) {
 	return printJSON(rep)
 }
 
+//====================================================================================
+// Data Retention Commands
+//====================================================================================
+
+func archiveEnvelope(c *cli.Context) (err error) {
+	var msg *api.SecureEnvelope
+	if msg, err = loadEnvelope(c.String("in")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// Decrypt the envelope to determine the record type of the payload
+	var handler *env.Envelope
+	if handler, err = env.Wrap(msg); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if handler, _, err = handler.Decrypt(); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var payload *api.Payload
+	if payload, err = handler.Payload(); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var archive *retention.Archive
+	if archive, err = openArchive(c, nil); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var record *retention.Record
+	if record, err = archive.Put(msg, retention.RecordType(payload), c.String("jurisdiction")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	return printJSON(record)
+}
+
+func purge(c *cli.Context) (err error) {
+	var policies retention.Policies
+	if policies, err = retention.LoadPolicies(c.String("policies")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var archive *retention.Archive
+	if archive, err = openArchive(c, policies); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	asOf := time.Now()
+	if ts := c.Timestamp("as-of"); ts != nil {
+		asOf = *ts
+	}
+
+	var report *retention.Report
+	if report, err = archive.Purge(asOf, c.Bool("dry-run")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if out := c.String("out"); out != "" {
+		var data []byte
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		if err = ioutil.WriteFile(out, data, 0644); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	if err = printJSON(report); err != nil {
+		return err
+	}
+
+	if len(report.Errors) > 0 {
+		return cli.Exit(fmt.Errorf("could not purge %d archived envelopes", len(report.Errors)), 1)
+	}
+	return nil
+}
+
 //====================================================================================
 // Helper Commands - Clients
 //====================================================================================
@@ -1248,6 +1402,25 @@ func dumpProto(msg proto.Message, path string) (err error) {
 	return nil
 }
 
+func openArchive(c *cli.Context, policies retention.Policies) (_ *retention.Archive, err error) {
+	root := c.String("archive")
+	keyDir := c.String("keys")
+	if keyDir == "" {
+		keyDir = filepath.Join(root, "keys")
+	}
+
+	var records *retention.FileStore
+	if records, err = retention.NewFileStore(filepath.Join(root, "records")); err != nil {
+		return nil, err
+	}
+
+	var keys *retention.FileKeyStore
+	if keys, err = retention.NewFileKeyStore(keyDir); err != nil {
+		return nil, err
+	}
+	return retention.New(records, keys, policies), nil
+}
+
 //====================================================================================
 // Helper Commands - Managing Secure Envelopes
 //====================================================================================
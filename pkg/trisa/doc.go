@@ -84,6 +84,12 @@ are handled and verified correctly.
 Package peers provides structs and methods to facilitate information exchanges
 to other members of the TRISA network and directory service lookups.
 
+`retention`
+
+Package retention applies data retention policies to archived secure envelopes by
+crypto-shredding: each archived envelope is wrapped with its own key and the key is
+destroyed once the retention period for the record type and jurisdiction elapses.
+
 It is important to note that most of the subpackages in this repository are
 independent and they are implemented and tested separately from other
 subpackages.
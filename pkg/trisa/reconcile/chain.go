@@ -0,0 +1,79 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// Settlement is a transaction that has been settled on a chain or network.
+type Settlement struct {
+	Txid        string    `json:"txid"`
+	Network     string    `json:"network"`
+	AssetType   string    `json:"asset_type,omitempty"`
+	Originator  string    `json:"originator,omitempty"`
+	Beneficiary string    `json:"beneficiary"`
+	Amount      float64   `json:"amount"`
+	Tag         string    `json:"tag,omitempty"`
+	SettledAt   time.Time `json:"settled_at"`
+}
+
+// ChainData provides settled on-chain transactions to reconcile TRISA exchanges
+// against. Implementations may be backed by a node, an indexer, a block explorer API,
+// or a VASP's own ledger. Settlements must be returned for every network that the
+// implementation knows about whose settlement time is in the half-open interval
+// [since, until).
+type ChainData interface {
+	Settlements(ctx context.Context, since, until time.Time) ([]*Settlement, error)
+}
+
+// FileChainData is a ChainData stand-in that reads settlements from a JSON file that
+// contains a list of Settlement objects. It is intended for testing and for
+// reconciling against exports from external systems.
+type FileChainData struct {
+	settlements []*Settlement
+}
+
+var _ ChainData = &FileChainData{}
+
+// LoadChainData reads settlements from the JSON file at the specified path.
+func LoadChainData(path string) (_ *FileChainData, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	chain := &FileChainData{}
+	if err = json.Unmarshal(data, &chain.settlements); err != nil {
+		return nil, fmt.Errorf("could not parse chain data: %s", err)
+	}
+
+	for i, settlement := range chain.settlements {
+		if settlement.Txid == "" {
+			return nil, fmt.Errorf("settlement %d is missing a txid", i)
+		}
+		if settlement.SettledAt.IsZero() {
+			return nil, fmt.Errorf("settlement %s is missing a settled at timestamp", settlement.Txid)
+		}
+	}
+
+	sort.SliceStable(chain.settlements, func(i, j int) bool {
+		return chain.settlements[i].SettledAt.Before(chain.settlements[j].SettledAt)
+	})
+	return chain, nil
+}
+
+// Settlements returns the settlements in the file within the specified interval.
+func (c *FileChainData) Settlements(ctx context.Context, since, until time.Time) ([]*Settlement, error) {
+	settlements := make([]*Settlement, 0)
+	for _, settlement := range c.settlements {
+		if settlement.SettledAt.Before(since) || !settlement.SettledAt.Before(until) {
+			continue
+		}
+		settlements = append(settlements, settlement)
+	}
+	return settlements, nil
+}
@@ -0,0 +1,9 @@
+package reconcile
+
+import "errors"
+
+var (
+	ErrNoTransaction = errors.New("exchange does not contain a transaction payload")
+	ErrNoSigningKey  = errors.New("no signing key available for counterparty, exchange keys first")
+	ErrNotCompleted  = errors.New("cannot follow up on an exchange that was not completed")
+)
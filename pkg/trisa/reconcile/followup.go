@@ -0,0 +1,87 @@
+package reconcile
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Counterparty is the remote peer that a follow-up envelope is sent to; it is
+// implemented by *peers.Peer, which should have already exchanged keys.
+type Counterparty interface {
+	SigningKey() *rsa.PublicKey
+	Transfer(in *api.SecureEnvelope) (*api.SecureEnvelope, error)
+}
+
+// FollowUpPayload returns a copy of the payload of the matched exchange with the
+// transaction updated with the final txid (and the settlement timestamp and
+// originator address if they were not originally specified). The identity payload is
+// unchanged and the sent at timestamp is updated to the current time.
+func FollowUpPayload(match *Match) (payload *api.Payload, err error) {
+	var orig *generic.Transaction
+	if orig, err = match.Exchange.Transaction(); err != nil {
+		return nil, err
+	}
+
+	tx := proto.Clone(orig).(*generic.Transaction)
+	tx.Txid = match.Settlement.Txid
+	if tx.Timestamp == "" {
+		tx.Timestamp = match.Settlement.SettledAt.Format(time.RFC3339)
+	}
+	if tx.Originator == "" {
+		tx.Originator = match.Settlement.Originator
+	}
+
+	payload = proto.Clone(match.Exchange.Payload).(*api.Payload)
+	if payload.Transaction, err = anypb.New(tx); err != nil {
+		return nil, fmt.Errorf("could not serialize follow up transaction: %s", err)
+	}
+
+	payload.SentAt = time.Now().Format(time.RFC3339)
+	payload.ReceivedAt = ""
+	return payload, nil
+}
+
+// FollowUp sends a follow-up envelope with the final txid to the counterparty of the
+// matched exchange. The follow-up uses the envelope ID of the original exchange so that
+// the counterparty can link it to the compliance information it already holds. The
+// envelope is sealed with the counterparty's signing key and any additional envelope
+// options are applied after the envelope ID and sealing key. If the counterparty
+// rejects the follow-up, the rejection is returned as the error along with the reply.
+func FollowUp(match *Match, peer Counterparty, opts ...envelope.Option) (reply *api.SecureEnvelope, err error) {
+	if !match.Exchange.Completed {
+		return nil, ErrNotCompleted
+	}
+
+	key := peer.SigningKey()
+	if key == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	var payload *api.Payload
+	if payload, err = FollowUpPayload(match); err != nil {
+		return nil, err
+	}
+
+	opts = append([]envelope.Option{envelope.WithEnvelopeID(match.Exchange.EnvelopeID), envelope.WithRSAPublicKey(key)}, opts...)
+
+	var msg *api.SecureEnvelope
+	if msg, _, err = envelope.Seal(payload, opts...); err != nil {
+		return nil, fmt.Errorf("could not seal follow up envelope: %s", err)
+	}
+
+	if reply, err = peer.Transfer(msg); err != nil {
+		return nil, err
+	}
+
+	if reject, iserr := envelope.Check(reply); iserr {
+		return reply, reject
+	}
+	return reply, nil
+}
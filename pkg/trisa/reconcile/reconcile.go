@@ -0,0 +1,373 @@
+/*
+Package reconcile matches TRISA information exchanges against transactions that have
+been settled on-chain. The txid of a generic.Transaction is often empty when the
+compliance exchange happens because the transaction has not yet been broadcast; once
+the transaction settles, the exchange should be linked to the final txid and the
+counterparty notified with a follow-up envelope.
+
+Reconciliation also surfaces two compliance problems: transfers that were settled
+on-chain without a completed TRISA exchange, and completed exchanges whose transfer
+was never settled. Settled transactions are supplied by a pluggable ChainData
+implementation; FileChainData is provided as a stand-in for testing and batch use.
+*/
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+)
+
+// Default reconciliation parameters.
+const (
+	DefaultWindow      = 24 * time.Hour
+	DefaultGracePeriod = 72 * time.Hour
+	DefaultTolerance   = 1e-9
+)
+
+// Exchange is a stored TRISA information exchange that should be reconciled.
+type Exchange struct {
+	EnvelopeID   string       // the id of the secure envelope of the exchange
+	Counterparty string       // the common name of the counterparty VASP
+	Payload      *api.Payload // the clear payload that was exchanged
+	Completed    bool         // true if the exchange was accepted by the beneficiary
+	ExchangedAt  time.Time    // when the exchange took place
+
+	transaction *generic.Transaction
+}
+
+// Transaction parses the generic transaction from the exchange payload.
+func (e *Exchange) Transaction() (_ *generic.Transaction, err error) {
+	if e.transaction != nil {
+		return e.transaction, nil
+	}
+
+	if e.Payload == nil || e.Payload.Transaction == nil {
+		return nil, ErrNoTransaction
+	}
+
+	tx := &generic.Transaction{}
+	if err = e.Payload.Transaction.UnmarshalTo(tx); err != nil {
+		return nil, fmt.Errorf("could not parse generic transaction for envelope %s: %s", e.EnvelopeID, err)
+	}
+
+	e.transaction = tx
+	return e.transaction, nil
+}
+
+// ExchangeStore provides access to stored exchanges that are to be reconciled.
+type ExchangeStore interface {
+	Exchanges(ctx context.Context, since, until time.Time) ([]*Exchange, error)
+}
+
+// Exchanges is an in-memory ExchangeStore.
+type Exchanges []*Exchange
+
+// Exchanges returns the exchanges that occurred in the half-open interval [since, until).
+func (e Exchanges) Exchanges(ctx context.Context, since, until time.Time) ([]*Exchange, error) {
+	exchanges := make([]*Exchange, 0, len(e))
+	for _, exchange := range e {
+		if exchange.ExchangedAt.Before(since) || !exchange.ExchangedAt.Before(until) {
+			continue
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, nil
+}
+
+// Reconciler matches exchanges from an ExchangeStore to settlements from ChainData.
+type Reconciler struct {
+	exchanges ExchangeStore
+	chain     ChainData
+	window    time.Duration
+	grace     time.Duration
+	tolerance float64
+}
+
+// Option configures a Reconciler.
+type Option func(r *Reconciler)
+
+// WithWindow sets the maximum time between an exchange and a settlement when the two
+// have to be matched on transaction details because the exchange has no txid.
+func WithWindow(window time.Duration) Option {
+	return func(r *Reconciler) {
+		r.window = window
+	}
+}
+
+// WithGracePeriod sets how long after a completed exchange a settlement may appear
+// before the exchange is flagged as never settled.
+func WithGracePeriod(grace time.Duration) Option {
+	return func(r *Reconciler) {
+		r.grace = grace
+	}
+}
+
+// WithTolerance sets the absolute difference allowed between the exchange amount and
+// the settled amount when matching on transaction details (e.g. to allow for fees).
+func WithTolerance(tolerance float64) Option {
+	return func(r *Reconciler) {
+		r.tolerance = tolerance
+	}
+}
+
+// New creates a Reconciler with the specified options.
+func New(exchanges ExchangeStore, chain ChainData, opts ...Option) *Reconciler {
+	r := &Reconciler{
+		exchanges: exchanges,
+		chain:     chain,
+		window:    DefaultWindow,
+		grace:     DefaultGracePeriod,
+		tolerance: DefaultTolerance,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// MatchMethod describes how an exchange was matched to a settlement.
+type MatchMethod string
+
+const (
+	MatchedByTxid    MatchMethod = "txid"
+	MatchedByDetails MatchMethod = "details"
+)
+
+// Match links an exchange to the settlement of its transaction.
+type Match struct {
+	Exchange   *Exchange
+	Settlement *Settlement
+	Method     MatchMethod
+}
+
+// NeedsFollowUp returns true if the counterparty was not sent the final txid during a
+// completed exchange and should be sent a follow-up envelope.
+func (m *Match) NeedsFollowUp() bool {
+	if !m.Exchange.Completed {
+		return false
+	}
+
+	tx, err := m.Exchange.Transaction()
+	return err == nil && tx.Txid != m.Settlement.Txid
+}
+
+// DiscrepancyKind classifies a reconciliation problem.
+type DiscrepancyKind string
+
+const (
+	// SettledWithoutExchange means a transfer was settled on-chain but there is no
+	// completed TRISA exchange for it; Exchange is set if an incomplete (e.g. rejected
+	// or pending) exchange matched the settlement.
+	SettledWithoutExchange DiscrepancyKind = "settled_without_exchange"
+
+	// NeverSettled means a TRISA exchange was completed but no matching settlement was
+	// found within the grace period.
+	NeverSettled DiscrepancyKind = "never_settled"
+)
+
+// Discrepancy is a reconciliation problem that requires compliance review.
+type Discrepancy struct {
+	Kind       DiscrepancyKind
+	Exchange   *Exchange
+	Settlement *Settlement
+}
+
+// Report is the result of a reconciliation run.
+type Report struct {
+	Since         time.Time
+	Until         time.Time
+	Matches       []*Match
+	Discrepancies []*Discrepancy
+	Pending       []*Exchange // completed exchanges still within their grace period
+	Errors        []error     // exchanges that could not be reconciled
+}
+
+// FollowUps returns the matches that require a follow-up envelope with the final txid.
+func (r *Report) FollowUps() []*Match {
+	matches := make([]*Match, 0)
+	for _, match := range r.Matches {
+		if match.NeedsFollowUp() {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// Reconcile exchanges made in the interval [since, until) with settlements. Exchanges
+// that include a txid are matched directly; otherwise they are matched to the closest
+// settlement within the window on the same network with the same beneficiary address
+// (and originator address and asset type, if specified) and amount. Settlements are
+// loaded for the interval extended by the window on both sides so that exchanges at
+// the edge of the interval can be matched, but only settlements inside the interval
+// are flagged if they have no exchange. Completed exchanges without a settlement are
+// flagged as never settled if the grace period elapsed before the end of the interval.
+func (r *Reconciler) Reconcile(ctx context.Context, since, until time.Time) (report *Report, err error) {
+	var exchanges []*Exchange
+	if exchanges, err = r.exchanges.Exchanges(ctx, since, until); err != nil {
+		return nil, fmt.Errorf("could not load exchanges: %s", err)
+	}
+
+	var settlements []*Settlement
+	if settlements, err = r.chain.Settlements(ctx, since.Add(-r.window), until.Add(r.window)); err != nil {
+		return nil, fmt.Errorf("could not load settlements: %s", err)
+	}
+
+	report = &Report{
+		Since:         since,
+		Until:         until,
+		Matches:       make([]*Match, 0),
+		Discrepancies: make([]*Discrepancy, 0),
+		Pending:       make([]*Exchange, 0),
+	}
+
+	// Sort exchanges so that the earliest exchanges claim settlements first and so that
+	// the report is deterministic.
+	sort.SliceStable(exchanges, func(i, j int) bool {
+		return exchanges[i].ExchangedAt.Before(exchanges[j].ExchangedAt)
+	})
+
+	// Index settlements by network and txid for direct matching
+	index := make(map[string]*Settlement, len(settlements))
+	for _, settlement := range settlements {
+		index[txkey(settlement.Network, settlement.Txid)] = settlement
+		index[txkey("", settlement.Txid)] = settlement
+	}
+
+	claimed := make(map[*Settlement]struct{}, len(settlements))
+	unmatched := make([]*Exchange, 0)
+
+	// First pass: match exchanges that have a txid
+	for _, exchange := range exchanges {
+		var tx *generic.Transaction
+		if tx, err = exchange.Transaction(); err != nil {
+			report.Errors = append(report.Errors, err)
+			continue
+		}
+
+		if tx.Txid != "" {
+			if settlement, ok := index[txkey(tx.Network, tx.Txid)]; ok {
+				if _, taken := claimed[settlement]; !taken {
+					claimed[settlement] = struct{}{}
+					report.addMatch(exchange, settlement, MatchedByTxid)
+					continue
+				}
+			}
+		}
+		unmatched = append(unmatched, exchange)
+	}
+
+	// Second pass: match the remaining exchanges on transaction details
+	for _, exchange := range unmatched {
+		if settlement := r.closest(exchange, settlements, claimed); settlement != nil {
+			claimed[settlement] = struct{}{}
+			report.addMatch(exchange, settlement, MatchedByDetails)
+			continue
+		}
+
+		if !exchange.Completed {
+			// Incomplete exchanges that were never settled are expected (e.g. rejected)
+			continue
+		}
+
+		if exchange.ExchangedAt.Add(r.grace).Before(until) {
+			report.Discrepancies = append(report.Discrepancies, &Discrepancy{Kind: NeverSettled, Exchange: exchange})
+		} else {
+			report.Pending = append(report.Pending, exchange)
+		}
+	}
+
+	// Settlements in the interval that were not claimed have no exchange at all
+	for _, settlement := range settlements {
+		if _, ok := claimed[settlement]; ok {
+			continue
+		}
+
+		if settlement.SettledAt.Before(since) || !settlement.SettledAt.Before(until) {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, &Discrepancy{Kind: SettledWithoutExchange, Settlement: settlement})
+	}
+	return report, nil
+}
+
+// Add a match to the report, flagging it if the matched exchange was not completed.
+func (r *Report) addMatch(exchange *Exchange, settlement *Settlement, method MatchMethod) {
+	r.Matches = append(r.Matches, &Match{Exchange: exchange, Settlement: settlement, Method: method})
+	if !exchange.Completed {
+		r.Discrepancies = append(r.Discrepancies, &Discrepancy{
+			Kind:       SettledWithoutExchange,
+			Exchange:   exchange,
+			Settlement: settlement,
+		})
+	}
+}
+
+// Find the unclaimed settlement closest in time to the exchange whose details match.
+func (r *Reconciler) closest(exchange *Exchange, settlements []*Settlement, claimed map[*Settlement]struct{}) (match *Settlement) {
+	tx, _ := exchange.Transaction()
+	if tx == nil || tx.Beneficiary == "" {
+		return nil
+	}
+
+	var best time.Duration
+	for _, settlement := range settlements {
+		if _, ok := claimed[settlement]; ok {
+			continue
+		}
+
+		if !r.matches(tx, settlement) {
+			continue
+		}
+
+		delta := settlement.SettledAt.Sub(exchange.ExchangedAt)
+		if delta < 0 {
+			delta = -delta
+		}
+
+		if delta > r.window {
+			continue
+		}
+
+		if match == nil || delta < best {
+			match, best = settlement, delta
+		}
+	}
+	return match
+}
+
+// Returns true if the transaction details of the exchange match the settlement.
+func (r *Reconciler) matches(tx *generic.Transaction, settlement *Settlement) bool {
+	if tx.Network != "" && !strings.EqualFold(tx.Network, settlement.Network) {
+		return false
+	}
+
+	if tx.Beneficiary != settlement.Beneficiary {
+		return false
+	}
+
+	if tx.Originator != "" && settlement.Originator != "" && tx.Originator != settlement.Originator {
+		return false
+	}
+
+	if tx.AssetType != "" && settlement.AssetType != "" && !strings.EqualFold(tx.AssetType, settlement.AssetType) {
+		return false
+	}
+
+	if tx.Tag != "" && settlement.Tag != "" && tx.Tag != settlement.Tag {
+		return false
+	}
+
+	return math.Abs(tx.Amount-settlement.Amount) <= r.tolerance
+}
+
+func txkey(network, txid string) string {
+	return strings.ToLower(network) + ":" + txid
+}
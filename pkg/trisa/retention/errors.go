@@ -0,0 +1,13 @@
+package retention
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("no archived record found for the specified envelope id")
+	ErrNoKey             = errors.New("no wrapping key found for the specified envelope id")
+	ErrShredded          = errors.New("archived record has been crypto-shredded and cannot be opened")
+	ErrNotUnsealed       = errors.New("only unsealed secure envelopes can be archived")
+	ErrNoEnvelopeID      = errors.New("cannot archive a secure envelope without an envelope id")
+	ErrInvalidPolicy     = errors.New("invalid retention policy: retention period must be positive")
+	ErrInvalidRecordType = errors.New("invalid retention policy: record type is required or use * for any")
+)
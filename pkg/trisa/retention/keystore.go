@@ -0,0 +1,109 @@
+package retention
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// KeyStore holds the per-envelope wrapping keys that protect archived records.
+// Destroying a key is the crypto-shredding operation: once the key is gone, the
+// encryption key and hmac secret of the archived envelope can no longer be recovered.
+// Implementations must return ErrNoKey from Get if the key does not exist and should
+// make Destroy idempotent. Keys should be kept separately from the record Store (e.g.
+// in an HSM or a different volume) so that backups of the records alone are not
+// sufficient to recover shredded data.
+type KeyStore interface {
+	Get(envelopeID string) ([]byte, error)
+	Put(envelopeID string, key []byte) error
+	Destroy(envelopeID string) error
+}
+
+// FileKeyStore is a KeyStore that writes each wrapping key to its own file.
+type FileKeyStore struct {
+	root string
+}
+
+var _ KeyStore = &FileKeyStore{}
+
+// NewFileKeyStore creates a FileKeyStore in the specified directory, creating the
+// directory if it does not already exist.
+func NewFileKeyStore(root string) (_ *FileKeyStore, err error) {
+	if err = os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &FileKeyStore{root: root}, nil
+}
+
+// Get the wrapping key for the specified envelope.
+func (s *FileKeyStore) Get(envelopeID string) (key []byte, err error) {
+	var path string
+	if path, err = s.path(envelopeID); err != nil {
+		return nil, err
+	}
+
+	if key, err = ioutil.ReadFile(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoKey
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// Put the wrapping key for the specified envelope.
+func (s *FileKeyStore) Put(envelopeID string, key []byte) (err error) {
+	var path string
+	if path, err = s.path(envelopeID); err != nil {
+		return err
+	}
+	return writeFile(path, key)
+}
+
+// Destroy the wrapping key by overwriting the key file with zeros before removing it.
+// Note that overwriting in place is best effort: journaling or copy-on-write file
+// systems and SSD wear leveling may retain old blocks, so production deployments
+// should prefer a KeyStore backed by an HSM or KMS that guarantees destruction.
+func (s *FileKeyStore) Destroy(envelopeID string) (err error) {
+	var path string
+	if path, err = s.path(envelopeID); err != nil {
+		return err
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY, 0); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var info os.FileInfo
+	if info, err = f.Stat(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err = f.WriteAt(make([]byte, info.Size()), 0); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *FileKeyStore) path(envelopeID string) (string, error) {
+	if err := checkID(envelopeID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, envelopeID+".key"), nil
+}
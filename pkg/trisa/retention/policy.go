@@ -0,0 +1,141 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/iso3166"
+)
+
+// Any is a wildcard that matches every record type or jurisdiction in a policy.
+const Any = "*"
+
+// Policy describes how long records of a specific type must be retained in a specific
+// jurisdiction before the wrapping key of the record is destroyed. The record type is
+// usually the fully qualified protobuf message name of the transaction payload (e.g.
+// trisa.data.generic.v1beta1.Transaction) and the jurisdiction is the ISO 3166-1
+// alpha-2 country code of the regulator whose rules apply. Either may be "*" to match
+// any value. The retention period is expressed in calendar years, months, and days so
+// that statutory periods such as "five years" are computed correctly across leap years.
+type Policy struct {
+	RecordType   string `json:"record_type"`
+	Jurisdiction string `json:"jurisdiction"`
+	Years        int    `json:"years,omitempty"`
+	Months       int    `json:"months,omitempty"`
+	Days         int    `json:"days,omitempty"`
+}
+
+// Validate the policy, normalizing the jurisdiction to an alpha-2 country code.
+func (p *Policy) Validate() (err error) {
+	if p.RecordType == "" {
+		return ErrInvalidRecordType
+	}
+
+	if p.Years < 0 || p.Months < 0 || p.Days < 0 || (p.Years == 0 && p.Months == 0 && p.Days == 0) {
+		return ErrInvalidPolicy
+	}
+
+	if p.Jurisdiction == "" {
+		p.Jurisdiction = Any
+	}
+
+	if p.Jurisdiction != Any {
+		var code iso3166.AlphaCode
+		if code, err = iso3166.Find(p.Jurisdiction); err != nil {
+			return fmt.Errorf("invalid retention policy: unknown jurisdiction %q", p.Jurisdiction)
+		}
+		p.Jurisdiction = code.Alpha2
+	}
+	return nil
+}
+
+// Matches returns true if the policy applies to the record type and jurisdiction.
+func (p *Policy) Matches(recordType, jurisdiction string) bool {
+	if p.RecordType != Any && p.RecordType != recordType {
+		return false
+	}
+	if p.Jurisdiction != Any && !strings.EqualFold(p.Jurisdiction, jurisdiction) {
+		return false
+	}
+	return true
+}
+
+// Expires returns the timestamp after which a record archived at the specified time
+// may be purged under this policy.
+func (p *Policy) Expires(archived time.Time) time.Time {
+	return archived.AddDate(p.Years, p.Months, p.Days)
+}
+
+// String returns a compact description of the policy for reporting.
+func (p *Policy) String() string {
+	return fmt.Sprintf("%s/%s:%dy%dm%dd", p.RecordType, p.Jurisdiction, p.Years, p.Months, p.Days)
+}
+
+// specificity ranks policies so that exact matches take precedence over wildcards;
+// an exact record type is considered more specific than an exact jurisdiction.
+func (p *Policy) specificity() (score int) {
+	if p.RecordType != Any {
+		score += 2
+	}
+	if p.Jurisdiction != Any {
+		score++
+	}
+	return score
+}
+
+// Policies is a set of retention policies that are applied to archived records.
+type Policies []*Policy
+
+// LoadPolicies reads a JSON list of retention policies from disk and validates them.
+func LoadPolicies(path string) (policies Policies, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("could not parse retention policies: %s", err)
+	}
+
+	if err = policies.Validate(); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// Validate all of the policies in the set.
+func (p Policies) Validate() (err error) {
+	for _, policy := range p {
+		if err = policy.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find the policy that governs the specified record type and jurisdiction. If more
+// than one policy matches, the most specific policy is returned; if multiple policies
+// are equally specific then the one with the longest retention period wins so that
+// records are never purged earlier than any applicable rule requires. Returns nil if
+// no policy matches, in which case the record should be retained indefinitely.
+func (p Policies) Find(recordType, jurisdiction string) (match *Policy) {
+	epoch := time.Unix(0, 0)
+	for _, policy := range p {
+		if !policy.Matches(recordType, jurisdiction) {
+			continue
+		}
+
+		if match == nil || policy.specificity() > match.specificity() {
+			match = policy
+			continue
+		}
+
+		if policy.specificity() == match.specificity() && policy.Expires(epoch).After(match.Expires(epoch)) {
+			match = policy
+		}
+	}
+	return match
+}
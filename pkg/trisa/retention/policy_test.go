@@ -0,0 +1,86 @@
+package retention_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trisa/retention"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := &retention.Policy{RecordType: retention.Any, Jurisdiction: "Singapore", Years: 5}
+	require.NoError(t, policy.Validate())
+	require.Equal(t, "SG", policy.Jurisdiction)
+
+	policy = &retention.Policy{RecordType: retention.Any, Years: 5}
+	require.NoError(t, policy.Validate())
+	require.Equal(t, retention.Any, policy.Jurisdiction)
+
+	policy = &retention.Policy{RecordType: retention.Any}
+	require.ErrorIs(t, policy.Validate(), retention.ErrInvalidPolicy)
+
+	policy = &retention.Policy{RecordType: retention.Any, Years: 5, Days: -1}
+	require.ErrorIs(t, policy.Validate(), retention.ErrInvalidPolicy)
+
+	policy = &retention.Policy{Jurisdiction: "US", Years: 5}
+	require.ErrorIs(t, policy.Validate(), retention.ErrInvalidRecordType)
+
+	policy = &retention.Policy{RecordType: retention.Any, Jurisdiction: "Atlantis", Years: 5}
+	require.Error(t, policy.Validate())
+}
+
+func TestPolicyExpires(t *testing.T) {
+	policy := &retention.Policy{RecordType: retention.Any, Years: 5, Months: 1, Days: 2}
+	archived := time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC), policy.Expires(archived))
+}
+
+func TestPoliciesFind(t *testing.T) {
+	policies := retention.Policies{
+		{RecordType: retention.Any, Jurisdiction: retention.Any, Years: 5},
+		{RecordType: retention.Any, Jurisdiction: "US", Years: 7},
+		{RecordType: "trisa.data.generic.v1beta1.Transaction", Jurisdiction: retention.Any, Years: 3},
+		{RecordType: "trisa.data.generic.v1beta1.Transaction", Jurisdiction: "US", Years: 1},
+		{RecordType: "trisa.data.generic.v1beta1.Pending", Jurisdiction: "DE", Years: 2},
+		{RecordType: "trisa.data.generic.v1beta1.Pending", Jurisdiction: "DE", Years: 4},
+	}
+	require.NoError(t, policies.Validate())
+
+	testCases := []struct {
+		recordType   string
+		jurisdiction string
+		expected     int
+	}{
+		{"trisa.data.generic.v1beta1.Transaction", "US", 1},
+		{"trisa.data.generic.v1beta1.Transaction", "us", 1},
+		{"trisa.data.generic.v1beta1.Transaction", "GB", 3},
+		{"trisa.data.generic.v1beta1.Pending", "US", 7},
+		{"trisa.data.generic.v1beta1.Pending", "DE", 4},
+		{"foo", "GB", 5},
+	}
+
+	for _, tc := range testCases {
+		policy := policies.Find(tc.recordType, tc.jurisdiction)
+		require.NotNil(t, policy, "expected a policy for %s in %s", tc.recordType, tc.jurisdiction)
+		require.Equal(t, tc.expected, policy.Years, "wrong policy matched for %s in %s", tc.recordType, tc.jurisdiction)
+	}
+
+	require.Nil(t, policies[1:2].Find("foo", "GB"))
+}
+
+func TestLoadPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"record_type": "*", "jurisdiction": "USA", "years": 5}]`), 0600))
+
+	policies, err := retention.LoadPolicies(path)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, "US", policies[0].Jurisdiction)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"record_type": "*", "jurisdiction": "USA"}]`), 0600))
+	_, err = retention.LoadPolicies(path)
+	require.ErrorIs(t, err, retention.ErrInvalidPolicy)
+}
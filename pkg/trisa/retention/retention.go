@@ -0,0 +1,269 @@
+/*
+Package retention implements data retention policies for archived secure envelopes
+using crypto-shredding. Travel rule regulations require VASPs to keep compliance
+records for a statutory period (which differs by jurisdiction and record type) while
+privacy regulations require that personal data is not kept longer than necessary.
+
+Archived envelopes are stored unsealed-then-wrapped: the encryption key and hmac
+secret of each envelope are sealed with a random, per-envelope AES-GCM wrapping key
+that is held in a separate KeyStore. When the retention period of a record elapses,
+the wrapping key is destroyed, rendering the payload unrecoverable without having to
+rewrite or locate every copy of the ciphertext (e.g. in backups). The envelope
+metadata (id, timestamp, algorithms, and the record type and jurisdiction) is kept so
+that auditors can see that a record existed and when it was purged.
+*/
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/iso3166"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto/aesgcm"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+)
+
+// Archive applies retention policies to secure envelopes held in a Store, using the
+// wrapping keys in the KeyStore to open or shred them.
+type Archive struct {
+	records  Store
+	keys     KeyStore
+	policies Policies
+}
+
+// New creates an archive from a record store, a key store, and retention policies.
+func New(records Store, keys KeyStore, policies Policies) *Archive {
+	return &Archive{records: records, keys: keys, policies: policies}
+}
+
+// RecordType returns the record type of a payload for use with retention policies,
+// which is the fully qualified protocol buffer message name of the transaction.
+func RecordType(payload *api.Payload) string {
+	if payload == nil || payload.Transaction == nil {
+		return ""
+	}
+	return string(payload.Transaction.MessageName())
+}
+
+// Put archives an unsealed secure envelope (e.g. one that was stored after it was
+// received and unsealed with the local private key). A new wrapping key is generated
+// and used to seal the envelope's encryption key and hmac secret; the wrapping key is
+// stored in the key store and the wrapped envelope in the record store. If a record
+// with the same envelope ID is already archived, it is replaced.
+func (a *Archive) Put(msg *api.SecureEnvelope, recordType, jurisdiction string) (record *Record, err error) {
+	var env *envelope.Envelope
+	if env, err = envelope.Wrap(msg); err != nil {
+		return nil, err
+	}
+
+	if env.ID() == "" {
+		return nil, ErrNoEnvelopeID
+	}
+
+	if state := env.State(); state != envelope.Unsealed && state != envelope.UnsealedError {
+		return nil, ErrNotUnsealed
+	}
+
+	if jurisdiction != "" {
+		var code iso3166.AlphaCode
+		if code, err = iso3166.Find(jurisdiction); err != nil {
+			return nil, fmt.Errorf("unknown jurisdiction %q", jurisdiction)
+		}
+		jurisdiction = code.Alpha2
+	}
+
+	// Create a random per-envelope wrapping key to seal the envelope with.
+	var wrap *aesgcm.AESGCM
+	if wrap, err = aesgcm.New(nil, nil); err != nil {
+		return nil, err
+	}
+
+	var wrapped *envelope.Envelope
+	if wrapped, _, err = env.Seal(envelope.WithSeal(wrap)); err != nil {
+		return nil, fmt.Errorf("could not wrap envelope keys: %s", err)
+	}
+
+	// Sealing resets the ordering timestamp; the archive should preserve the original.
+	wrapped.Proto().Timestamp = msg.Timestamp
+
+	record = &Record{
+		EnvelopeID:   env.ID(),
+		RecordType:   recordType,
+		Jurisdiction: jurisdiction,
+		ArchivedAt:   time.Now().UTC(),
+	}
+
+	if record.Envelope, err = proto.Marshal(wrapped.Proto()); err != nil {
+		return nil, fmt.Errorf("could not marshal wrapped envelope: %s", err)
+	}
+
+	if err = a.keys.Put(record.EnvelopeID, wrap.EncryptionKey()); err != nil {
+		return nil, fmt.Errorf("could not store wrapping key: %s", err)
+	}
+
+	if err = a.records.Put(record); err != nil {
+		// Do not leave an orphaned key behind if the record could not be written.
+		a.keys.Destroy(record.EnvelopeID)
+		return nil, fmt.Errorf("could not store archived record: %s", err)
+	}
+	return record, nil
+}
+
+// Get the archived record metadata without unwrapping the envelope.
+func (a *Archive) Get(envelopeID string) (*Record, error) {
+	return a.records.Get(envelopeID)
+}
+
+// Open returns the unsealed secure envelope for the archived record, which can then be
+// decrypted using the envelope package. ErrShredded is returned if the retention
+// period of the record has elapsed and its wrapping key has been destroyed.
+func (a *Archive) Open(envelopeID string) (_ *api.SecureEnvelope, err error) {
+	var record *Record
+	if record, err = a.records.Get(envelopeID); err != nil {
+		return nil, err
+	}
+
+	if record.Shredded() {
+		return nil, ErrShredded
+	}
+
+	var key []byte
+	if key, err = a.keys.Get(envelopeID); err != nil {
+		return nil, err
+	}
+
+	var wrap *aesgcm.AESGCM
+	if wrap, err = aesgcm.New(key, key); err != nil {
+		return nil, err
+	}
+
+	var msg *api.SecureEnvelope
+	if msg, err = record.SecureEnvelope(); err != nil {
+		return nil, err
+	}
+
+	var env *envelope.Envelope
+	if env, err = envelope.Wrap(msg); err != nil {
+		return nil, err
+	}
+
+	var unwrapped *envelope.Envelope
+	if unwrapped, _, err = env.Unseal(envelope.WithSeal(wrap)); err != nil {
+		return nil, fmt.Errorf("could not unwrap envelope keys: %s", err)
+	}
+
+	unwrapped.Proto().Timestamp = msg.Timestamp
+	return unwrapped.Proto(), nil
+}
+
+// Shred destroys the wrapping key of the archived record and marks the record as
+// shredded. The record metadata is retained. Shredding is irreversible.
+func (a *Archive) Shred(envelopeID string) (record *Record, err error) {
+	if record, err = a.records.Get(envelopeID); err != nil {
+		return nil, err
+	}
+
+	if err = a.keys.Destroy(envelopeID); err != nil {
+		return nil, fmt.Errorf("could not destroy wrapping key: %s", err)
+	}
+
+	if !record.Shredded() {
+		record.ShreddedAt = time.Now().UTC()
+		if err = a.records.Put(record); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// Report describes the outcome of a purge run.
+type Report struct {
+	Started         time.Time `json:"started"`
+	Finished        time.Time `json:"finished"`
+	AsOf            time.Time `json:"as_of"`
+	DryRun          bool      `json:"dry_run"`
+	Scanned         int       `json:"scanned"`
+	Retained        int       `json:"retained"`
+	Unmatched       int       `json:"unmatched"`
+	AlreadyShredded int       `json:"already_shredded"`
+	Purged          []*Purged `json:"purged"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+// Purged describes a single record that was (or in a dry run, would be) shredded.
+type Purged struct {
+	EnvelopeID   string    `json:"envelope_id"`
+	RecordType   string    `json:"record_type"`
+	Jurisdiction string    `json:"jurisdiction"`
+	Policy       string    `json:"policy"`
+	ArchivedAt   time.Time `json:"archived_at"`
+	ExpiredAt    time.Time `json:"expired_at"`
+}
+
+// Purge shreds every archived record whose retention period has elapsed as of the
+// specified time. Records that do not match any policy are retained indefinitely and
+// counted as unmatched so that gaps in the policies can be identified. If dryRun is
+// true, the report lists the records that would be purged without shredding them.
+// Errors shredding individual records are collected in the report so that a single
+// failure does not prevent other records from being purged.
+func (a *Archive) Purge(asOf time.Time, dryRun bool) (report *Report, err error) {
+	report = &Report{
+		Started: time.Now().UTC(),
+		AsOf:    asOf,
+		DryRun:  dryRun,
+		Purged:  make([]*Purged, 0),
+	}
+
+	var records []*Record
+	if records, err = a.records.List(); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		report.Scanned++
+		if record.Shredded() {
+			report.AlreadyShredded++
+			continue
+		}
+
+		policy := a.policies.Find(record.RecordType, record.Jurisdiction)
+		if policy == nil {
+			report.Unmatched++
+			continue
+		}
+
+		expires := policy.Expires(record.ArchivedAt)
+		if asOf.Before(expires) {
+			report.Retained++
+			continue
+		}
+
+		if !dryRun {
+			if _, err = a.Shred(record.EnvelopeID); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", record.EnvelopeID, err))
+				continue
+			}
+		}
+
+		report.Purged = append(report.Purged, &Purged{
+			EnvelopeID:   record.EnvelopeID,
+			RecordType:   record.RecordType,
+			Jurisdiction: record.Jurisdiction,
+			Policy:       policy.String(),
+			ArchivedAt:   record.ArchivedAt,
+			ExpiredAt:    expires,
+		})
+	}
+
+	report.Finished = time.Now().UTC()
+	return report, nil
+}
+
+// IsShredded returns true if the error indicates the record can no longer be opened
+// because its wrapping key has been destroyed.
+func IsShredded(err error) bool {
+	return errors.Is(err, ErrShredded) || errors.Is(err, ErrNoKey)
+}
@@ -0,0 +1,208 @@
+package retention_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trisa/retention"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestArchive(t *testing.T) {
+	archive, _ := makeArchive(t, retention.Policies{
+		{RecordType: retention.Any, Jurisdiction: "US", Years: 5},
+	})
+
+	payload := makePayload(t)
+	msg := makeUnsealed(t, payload)
+
+	record, err := archive.Put(msg, retention.RecordType(payload), "USA")
+	require.NoError(t, err)
+	require.Equal(t, msg.Id, record.EnvelopeID)
+	require.Equal(t, "trisa.data.generic.v1beta1.Transaction", record.RecordType)
+	require.Equal(t, "US", record.Jurisdiction, "jurisdiction should be normalized")
+	require.False(t, record.Shredded())
+
+	// The archived envelope must not contain the keys in the clear
+	wrapped, err := record.SecureEnvelope()
+	require.NoError(t, err)
+	require.True(t, wrapped.Sealed)
+	require.NotEqual(t, msg.EncryptionKey, wrapped.EncryptionKey)
+	require.NotEqual(t, msg.HmacSecret, wrapped.HmacSecret)
+	require.Equal(t, msg.Timestamp, wrapped.Timestamp)
+
+	// Opening the archived envelope should return the original unsealed envelope
+	opened, err := archive.Open(msg.Id)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(msg, opened), "opened envelope does not match archived envelope")
+
+	env, err := envelope.Wrap(opened)
+	require.NoError(t, err)
+	clear, _, err := env.Decrypt()
+	require.NoError(t, err)
+	decrypted, err := clear.Payload()
+	require.NoError(t, err)
+	require.True(t, proto.Equal(payload, decrypted))
+
+	// Only unsealed envelopes can be archived
+	_, err = archive.Put(&api.SecureEnvelope{Id: "foo"}, "", "")
+	require.ErrorIs(t, err, retention.ErrNotUnsealed)
+
+	_, err = archive.Open("notarecord")
+	require.ErrorIs(t, err, retention.ErrNotFound)
+}
+
+func TestShred(t *testing.T) {
+	archive, keydir := makeArchive(t, nil)
+	msg := makeUnsealed(t, makePayload(t))
+
+	_, err := archive.Put(msg, "", "")
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(keydir, msg.Id+".key"))
+
+	record, err := archive.Shred(msg.Id)
+	require.NoError(t, err)
+	require.True(t, record.Shredded())
+	require.NoFileExists(t, filepath.Join(keydir, msg.Id+".key"))
+
+	// Metadata is retained after shredding but the envelope cannot be opened
+	record, err = archive.Get(msg.Id)
+	require.NoError(t, err)
+	require.True(t, record.Shredded())
+	require.NotEmpty(t, record.Envelope)
+
+	_, err = archive.Open(msg.Id)
+	require.ErrorIs(t, err, retention.ErrShredded)
+	require.True(t, retention.IsShredded(err))
+
+	// Shredding is idempotent
+	_, err = archive.Shred(msg.Id)
+	require.NoError(t, err)
+}
+
+func TestPurge(t *testing.T) {
+	archive, _ := makeArchive(t, retention.Policies{
+		{RecordType: retention.Any, Jurisdiction: retention.Any, Years: 5},
+		{RecordType: retention.Any, Jurisdiction: "DE", Years: 10},
+		{RecordType: "trisa.data.generic.v1beta1.Transaction", Jurisdiction: "SG", Days: 30},
+	})
+
+	payload := makePayload(t)
+	recordType := retention.RecordType(payload)
+
+	us := makeUnsealed(t, payload)
+	_, err := archive.Put(us, recordType, "US")
+	require.NoError(t, err)
+
+	de := makeUnsealed(t, payload)
+	_, err = archive.Put(de, recordType, "DE")
+	require.NoError(t, err)
+
+	sg := makeUnsealed(t, payload)
+	_, err = archive.Put(sg, recordType, "SG")
+	require.NoError(t, err)
+
+	// Nothing has expired yet
+	report, err := archive.Purge(time.Now(), false)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Scanned)
+	require.Equal(t, 3, report.Retained)
+	require.Len(t, report.Purged, 0)
+
+	// A dry run should report but not shred the expired SG record
+	report, err = archive.Purge(time.Now().AddDate(0, 2, 0), true)
+	require.NoError(t, err)
+	require.Len(t, report.Purged, 1)
+	require.Equal(t, sg.Id, report.Purged[0].EnvelopeID)
+	_, err = archive.Open(sg.Id)
+	require.NoError(t, err)
+
+	// After six years only the DE record should remain
+	report, err = archive.Purge(time.Now().AddDate(6, 0, 0), false)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Scanned)
+	require.Equal(t, 1, report.Retained)
+	require.Len(t, report.Purged, 2)
+	require.Empty(t, report.Errors)
+
+	_, err = archive.Open(us.Id)
+	require.ErrorIs(t, err, retention.ErrShredded)
+	_, err = archive.Open(sg.Id)
+	require.ErrorIs(t, err, retention.ErrShredded)
+	_, err = archive.Open(de.Id)
+	require.NoError(t, err)
+
+	// Subsequent purges count previously shredded records
+	report, err = archive.Purge(time.Now().AddDate(6, 0, 0), false)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.AlreadyShredded)
+	require.Len(t, report.Purged, 0)
+}
+
+func TestPurgeUnmatched(t *testing.T) {
+	archive, _ := makeArchive(t, retention.Policies{
+		{RecordType: retention.Any, Jurisdiction: "US", Years: 1},
+	})
+
+	msg := makeUnsealed(t, makePayload(t))
+	_, err := archive.Put(msg, "", "CA")
+	require.NoError(t, err)
+
+	// Records that do not match a policy are never purged
+	report, err := archive.Purge(time.Now().AddDate(100, 0, 0), false)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Unmatched)
+	require.Len(t, report.Purged, 0)
+
+	_, err = archive.Open(msg.Id)
+	require.NoError(t, err)
+}
+
+func makeArchive(t *testing.T, policies retention.Policies) (_ *retention.Archive, keydir string) {
+	require.NoError(t, policies.Validate())
+
+	dir := t.TempDir()
+	keydir = filepath.Join(dir, "keys")
+
+	records, err := retention.NewFileStore(filepath.Join(dir, "records"))
+	require.NoError(t, err)
+
+	keys, err := retention.NewFileKeyStore(keydir)
+	require.NoError(t, err)
+
+	return retention.New(records, keys, policies), keydir
+}
+
+func makePayload(t *testing.T) *api.Payload {
+	identity, err := anypb.New(&ivms101.IdentityPayload{})
+	require.NoError(t, err)
+
+	transaction, err := anypb.New(&generic.Transaction{
+		Txid:    "3a7cbc31-08ae-4f72-bcb7-39ac9c9c3fc8",
+		Network: "BTC",
+		Amount:  0.42,
+	})
+	require.NoError(t, err)
+
+	return &api.Payload{
+		Identity:    identity,
+		Transaction: transaction,
+		SentAt:      time.Now().Format(time.RFC3339),
+	}
+}
+
+func makeUnsealed(t *testing.T, payload *api.Payload) *api.SecureEnvelope {
+	env, err := envelope.New(payload)
+	require.NoError(t, err)
+
+	env, _, err = env.Encrypt()
+	require.NoError(t, err)
+	return env.Proto()
+}
@@ -0,0 +1,168 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Record is an archived secure envelope along with the metadata required to apply
+// retention policies to it. The envelope is stored sealed with a per-envelope wrapping
+// key held in a KeyStore; destroying that key renders the payload unrecoverable while
+// leaving the metadata (and the ciphertext) in place for auditing.
+type Record struct {
+	EnvelopeID   string    `json:"envelope_id"`
+	RecordType   string    `json:"record_type"`
+	Jurisdiction string    `json:"jurisdiction"`
+	ArchivedAt   time.Time `json:"archived_at"`
+	ShreddedAt   time.Time `json:"shredded_at,omitempty"`
+	Envelope     []byte    `json:"envelope"`
+}
+
+// Shredded returns true if the wrapping key of the record has been destroyed.
+func (r *Record) Shredded() bool {
+	return !r.ShreddedAt.IsZero()
+}
+
+// SecureEnvelope unmarshals the archived (wrapped) secure envelope.
+func (r *Record) SecureEnvelope() (msg *api.SecureEnvelope, err error) {
+	msg = &api.SecureEnvelope{}
+	if err = proto.Unmarshal(r.Envelope, msg); err != nil {
+		return nil, fmt.Errorf("could not unmarshal archived envelope: %s", err)
+	}
+	return msg, nil
+}
+
+// Store persists archived records. Implementations must return ErrNotFound from Get
+// if the record does not exist.
+type Store interface {
+	Get(envelopeID string) (*Record, error)
+	Put(record *Record) error
+	List() ([]*Record, error)
+}
+
+// FileStore is a Store that writes each record as a JSON document in a directory.
+type FileStore struct {
+	root string
+}
+
+var _ Store = &FileStore{}
+
+// NewFileStore creates a FileStore in the specified directory, creating the directory
+// if it does not already exist.
+func NewFileStore(root string) (_ *FileStore, err error) {
+	if err = os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{root: root}, nil
+}
+
+// Get the archived record for the specified envelope ID.
+func (s *FileStore) Get(envelopeID string) (record *Record, err error) {
+	var path string
+	if path, err = s.path(envelopeID); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	record = &Record{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("could not parse archived record: %s", err)
+	}
+	return record, nil
+}
+
+// Put writes the record to disk, replacing the record if it already exists. The record
+// is written to a temporary file and renamed so that a crash does not corrupt it.
+func (s *FileStore) Put(record *Record) (err error) {
+	var path string
+	if path, err = s.path(record.EnvelopeID); err != nil {
+		return err
+	}
+
+	var data []byte
+	if data, err = json.Marshal(record); err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// List all archived records ordered by the time they were archived.
+func (s *FileStore) List() (records []*Record, err error) {
+	var paths []string
+	if paths, err = filepath.Glob(filepath.Join(s.root, "*.json")); err != nil {
+		return nil, err
+	}
+
+	records = make([]*Record, 0, len(paths))
+	for _, path := range paths {
+		var record *Record
+		if record, err = s.Get(strings.TrimSuffix(filepath.Base(path), ".json")); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ArchivedAt.Before(records[j].ArchivedAt)
+	})
+	return records, nil
+}
+
+func (s *FileStore) path(envelopeID string) (string, error) {
+	if err := checkID(envelopeID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, envelopeID+".json"), nil
+}
+
+// checkID ensures an envelope ID can safely be used as a file name.
+func checkID(envelopeID string) error {
+	if envelopeID == "" {
+		return ErrNoEnvelopeID
+	}
+	if strings.ContainsAny(envelopeID, `/\`) || envelopeID == "." || envelopeID == ".." {
+		return fmt.Errorf("envelope id %q cannot be used as an archive key", envelopeID)
+	}
+	return nil
+}
+
+// writeFile atomically writes data to the path with owner-only permissions.
+func writeFile(path string, data []byte) (err error) {
+	var f *os.File
+	if f, err = ioutil.TempFile(filepath.Dir(path), ".tmp-"); err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}